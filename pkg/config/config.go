@@ -0,0 +1,31 @@
+package config
+
+// Provider holds the configuration for a single monitoring provider, as loaded
+// from the controller's configuration file or environment.
+type Provider struct {
+	Name          string
+	ApiKey        string
+	ApiURL        string
+	Username      string
+	AlertContacts string
+
+	// ApiVersion selects which StatusCake REST API generation to target ("v1" or
+	// "v2"). Defaults to "v1" for back-compat with existing configuration.
+	ApiVersion string
+
+	// MaxRetries caps how many times a provider's HTTP client retries a request
+	// on rate-limiting, 5xx responses or network errors. Defaults to 3 when unset.
+	MaxRetries int
+	// BaseBackoffMilliseconds is the starting delay for exponential backoff
+	// between retries. Defaults to 500ms when unset.
+	BaseBackoffMilliseconds int
+	// MaxBackoffSeconds caps the exponential backoff delay between retries.
+	// Defaults to 30s when unset.
+	MaxBackoffSeconds int
+	// CircuitBreakerThreshold is the number of consecutive request failures that
+	// trips the circuit breaker. Defaults to 5 when unset.
+	CircuitBreakerThreshold int
+	// CircuitBreakerResetSeconds is how long the circuit breaker stays open
+	// before allowing requests through again. Defaults to 60s when unset.
+	CircuitBreakerResetSeconds int
+}
@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveProviderRequestIncrementsCounterByResult(t *testing.T) {
+	ProviderRequestsTotal.Reset()
+
+	ObserveProviderRequest("statuscake", "GetAll", time.Now(), true)
+	ObserveProviderRequest("statuscake", "GetAll", time.Now(), false)
+
+	if got := testutil.ToFloat64(ProviderRequestsTotal.WithLabelValues("statuscake", "GetAll", "success")); got != 1 {
+		t.Errorf("expected 1 success request, got %v", got)
+	}
+	if got := testutil.ToFloat64(ProviderRequestsTotal.WithLabelValues("statuscake", "GetAll", "error")); got != 1 {
+		t.Errorf("expected 1 error request, got %v", got)
+	}
+}
@@ -4,34 +4,153 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	endpointmonitorv1alpha1 "github.com/stakater/IngressMonitorController/v2/api/v1alpha1"
 	"github.com/stakater/IngressMonitorController/v2/pkg/config"
+	"github.com/stakater/IngressMonitorController/v2/pkg/metrics"
 	"github.com/stakater/IngressMonitorController/v2/pkg/models"
 )
 
 var log = logf.Log.WithName("statuscake-monitor")
 
+const (
+	statusCakeProviderLabel = "statuscake"
+
+	operationGetAll = "GetAll"
+	operationAdd    = "Add"
+	operationUpdate = "Update"
+	operationRemove = "Remove"
+)
+
 // StatusCakeMonitorService is the service structure for StatusCake
 type StatusCakeMonitorService struct {
-	apiKey   string
-	url      string
-	username string
-	cgroup   string
-	client   *http.Client
+	apiKey     string
+	url        string
+	username   string
+	cgroup     string
+	apiVersion string
+	client     *http.Client
+	v2         *statusCakeV2Client
+}
+
+// StatusCakeTestDetails mirrors the payload returned by /API/Tests/Details, which
+// exposes the full test configuration, unlike the abbreviated /API/Tests/ list
+// used by GetAll.
+type StatusCakeTestDetails struct {
+	TestID         int      `json:"TestID"`
+	TestType       string   `json:"TestType"`
+	WebsiteName    string   `json:"WebsiteName"`
+	WebsiteURL     string   `json:"WebsiteURL"`
+	ContactGroup   string   `json:"ContactGroup"`
+	CheckRate      int      `json:"CheckRate"`
+	Confirmation   int      `json:"Confirmation"`
+	TriggerRate    int      `json:"TriggerRate"`
+	Port           int      `json:"Port"`
+	Paused         bool     `json:"Paused"`
+	FollowRedirect bool     `json:"FollowRedirect"`
+	EnableSSLAlert bool     `json:"EnableSSLAlert"`
+	RealBrowser    bool     `json:"RealBrowser"`
+	StatusCodes    string   `json:"StatusCodes"`
+	TestTags       string   `json:"TestTags"`
+	NodeLocations  []string `json:"NodeLocations"`
+	PingURL        string   `json:"PingURL"`
+	BasicUser      string   `json:"BasicUser"`
 }
 
+// toMonitor converts a StatusCakeTestDetails response into the same models.Monitor
+// shape buildUpsertForm expects, so the live test and the desired state can be
+// normalized and diffed identically.
+func (d StatusCakeTestDetails) toMonitor() models.Monitor {
+	return models.Monitor{
+		ID:   strconv.Itoa(d.TestID),
+		Name: d.WebsiteName,
+		URL:  d.WebsiteURL,
+		Config: &endpointmonitorv1alpha1.StatusCakeConfig{
+			CheckRate:      d.CheckRate,
+			TestType:       d.TestType,
+			ContactGroup:   d.ContactGroup,
+			TestTags:       d.TestTags,
+			StatusCodes:    d.StatusCodes,
+			Paused:         d.Paused,
+			FollowRedirect: d.FollowRedirect,
+			EnableSSLAlert: d.EnableSSLAlert,
+			RealBrowser:    d.RealBrowser,
+			PingURL:        d.PingURL,
+			NodeLocations:  strings.Join(d.NodeLocations, ","),
+			TriggerRate:    d.TriggerRate,
+			Port:           d.Port,
+			Confirmation:   d.Confirmation,
+			BasicAuthUser:  d.BasicUser,
+		},
+	}
+}
+
+// getTestDetails fetches the full configuration of an existing test so it can be
+// compared against the desired state before deciding whether an Update is needed.
+func (service *StatusCakeMonitorService) getTestDetails(id string) (*StatusCakeTestDetails, error) {
+	u, err := url.Parse(service.url)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/API/Tests/Details"
+	u.Scheme = "https"
+	query := u.Query()
+	query.Set("TestID", id)
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("API", service.apiKey)
+	req.Header.Add("Username", service.username)
+	resp, err := service.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Details Request failed for TestID: %s with status code %d", id, resp.StatusCode)
+	}
+	var details StatusCakeTestDetails
+	if err := json.NewDecoder(resp.Body).Decode(&details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// formsEqual reports whether two upsert forms are identical, i.e. whether applying
+// newMonitor's form to StatusCake would be a no-op against the form already in effect.
+func formsEqual(oldForm url.Values, newForm url.Values) bool {
+	return reflect.DeepEqual(oldForm, newForm)
+}
+
+// Equal fetches the currently configured test and compares it against newMonitor,
+// both normalized through buildUpsertForm, so the controller only calls Update when
+// the derived forms actually differ.
 func (monitor *StatusCakeMonitorService) Equal(oldMonitor models.Monitor, newMonitor models.Monitor) bool {
-	// TODO: Retrieve oldMonitor config and compare it here
-	return false
+	if monitor.apiVersion == apiVersionV2 {
+		return monitor.v2.Equal(oldMonitor, newMonitor)
+	}
+
+	details, err := monitor.getTestDetails(oldMonitor.ID)
+	if err != nil {
+		log.Error(err, "Unable to retrieve monitor details for TestID: "+oldMonitor.ID)
+		return false
+	}
+	oldForm := buildUpsertForm(details.toMonitor(), monitor.cgroup)
+	newForm := buildUpsertForm(newMonitor, monitor.cgroup)
+	return formsEqual(oldForm, newForm)
 }
 
 // buildUpsertForm function is used to create the form needed to Add or update a monitor
@@ -195,11 +314,23 @@ func (service *StatusCakeMonitorService) Setup(p config.Provider) {
 	service.url = p.ApiURL
 	service.username = p.Username
 	service.cgroup = p.AlertContacts
-	service.client = &http.Client{}
+	service.client = &http.Client{Transport: newResilientTransport(p, statusCakeProviderLabel)}
+
+	service.apiVersion = p.ApiVersion
+	if service.apiVersion == "" {
+		service.apiVersion = apiVersionV1
+	}
+	if service.apiVersion == apiVersionV2 {
+		service.v2 = newStatusCakeV2Client(p.ApiKey, p.ApiURL, p.AlertContacts, service.client)
+	}
 }
 
 // GetByName function will Get a monitor by it's name
 func (service *StatusCakeMonitorService) GetByName(name string) (*models.Monitor, error) {
+	if service.apiVersion == apiVersionV2 {
+		return service.v2.GetByName(name)
+	}
+
 	monitors := service.GetAll()
 	for _, monitor := range monitors {
 		if monitor.Name == name {
@@ -212,6 +343,21 @@ func (service *StatusCakeMonitorService) GetByName(name string) (*models.Monitor
 
 // GetAll function will fetch all monitors
 func (service *StatusCakeMonitorService) GetAll() []models.Monitor {
+	start := time.Now()
+	var all []models.Monitor
+	if service.apiVersion == apiVersionV2 {
+		all = service.v2.GetAll()
+	} else {
+		all = service.getAllV1()
+	}
+	metrics.ObserveProviderRequest(statusCakeProviderLabel, operationGetAll, start, all != nil)
+	if all != nil {
+		metrics.MonitorsManaged.WithLabelValues(statusCakeProviderLabel).Set(float64(len(all)))
+	}
+	return all
+}
+
+func (service *StatusCakeMonitorService) getAllV1() []models.Monitor {
 	u, err := url.Parse(service.url)
 	if err != nil {
 		log.Error(err, "Unable to Parse monitor URL")
@@ -246,10 +392,21 @@ func (service *StatusCakeMonitorService) GetAll() []models.Monitor {
 
 // Add will create a new Monitor
 func (service *StatusCakeMonitorService) Add(m models.Monitor) {
+	start := time.Now()
+	var success bool
+	if service.apiVersion == apiVersionV2 {
+		success = service.v2.Add(m)
+	} else {
+		success = service.addV1(m)
+	}
+	metrics.ObserveProviderRequest(statusCakeProviderLabel, operationAdd, start, success)
+}
+
+func (service *StatusCakeMonitorService) addV1(m models.Monitor) bool {
 	u, err := url.Parse(service.url)
 	if err != nil {
 		log.Error(err, "Unable to Parse monitor URL")
-		return
+		return false
 	}
 	u.Path = "/API/Tests/Update"
 	u.Scheme = "https"
@@ -257,21 +414,21 @@ func (service *StatusCakeMonitorService) Add(m models.Monitor) {
 	req, err := http.NewRequest("PUT", u.String(), bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		log.Error(err, "Unable to create http request")
-		return
+		return false
 	}
 	req.Header.Add("API", service.apiKey)
 	req.Header.Add("Username", service.username)
 	resp, err := service.client.Do(req)
 	if err != nil {
 		log.Error(err, "Unable to make HTTP call")
-		return
+		return false
 	}
 	if resp.StatusCode == http.StatusOK {
 		var fa StatusCakeUpsertResponse
 		err := json.NewDecoder(resp.Body).Decode(&fa)
 		if err != nil {
 			log.Error(err, "Unable to decode http response")
-			return
+			return false
 		}
 		if fa.Success {
 			log.Info("Monitor Added: " + strconv.Itoa(fa.InsertID))
@@ -279,23 +436,35 @@ func (service *StatusCakeMonitorService) Add(m models.Monitor) {
 			log.Info("Monitor couldn't be added: " + m.Name)
 			log.Info(fa.Message)
 		}
-	} else {
-		bodyBytes, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			log.Error(err, "Unable to read response")
-			os.Exit(1)
-		}
-		log.Error(nil, "Insert Request failed for name: "+m.Name+" with status code "+strconv.Itoa(resp.StatusCode))
-		log.Error(nil, string(bodyBytes))
+		return fa.Success
+	}
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Error(err, "Unable to read response")
+		os.Exit(1)
 	}
+	log.Error(nil, "Insert Request failed for name: "+m.Name+" with status code "+strconv.Itoa(resp.StatusCode))
+	log.Error(nil, string(bodyBytes))
+	return false
 }
 
 // Update will update an existing Monitor
 func (service *StatusCakeMonitorService) Update(m models.Monitor) {
+	start := time.Now()
+	var success bool
+	if service.apiVersion == apiVersionV2 {
+		success = service.v2.Update(m)
+	} else {
+		success = service.updateV1(m)
+	}
+	metrics.ObserveProviderRequest(statusCakeProviderLabel, operationUpdate, start, success)
+}
+
+func (service *StatusCakeMonitorService) updateV1(m models.Monitor) bool {
 	u, err := url.Parse(service.url)
 	if err != nil {
 		log.Error(err, "Unable to Parse monitor URL")
-		return
+		return false
 	}
 	u.Path = "/API/Tests/Update"
 	u.Scheme = "https"
@@ -304,21 +473,21 @@ func (service *StatusCakeMonitorService) Update(m models.Monitor) {
 	req, err := http.NewRequest("PUT", u.String(), bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		log.Error(err, "Unable to create http request")
-		return
+		return false
 	}
 	req.Header.Add("API", service.apiKey)
 	req.Header.Add("Username", service.username)
 	resp, err := service.client.Do(req)
 	if err != nil {
 		log.Error(err, "Unable to make HTTP call")
-		return
+		return false
 	}
 	if resp.StatusCode == http.StatusOK {
 		var fa StatusCakeUpsertResponse
 		err := json.NewDecoder(resp.Body).Decode(&fa)
 		if err != nil {
 			log.Error(err, "Unable to decode http response")
-			return
+			return false
 		}
 		if fa.Success {
 			log.Info("Monitor Updated: " + m.Name)
@@ -326,17 +495,29 @@ func (service *StatusCakeMonitorService) Update(m models.Monitor) {
 			log.V(1).Info("Monitor couldn't be updated: " + m.Name)
 			log.V(1).Info(fa.Message)
 		}
-	} else {
-		log.Error(nil, "Update Request failed for name: "+m.Name)
+		return fa.Success
 	}
+	log.Error(nil, "Update Request failed for name: "+m.Name)
+	return false
 }
 
 // Remove will delete an existing Monitor
 func (service *StatusCakeMonitorService) Remove(m models.Monitor) {
+	start := time.Now()
+	var success bool
+	if service.apiVersion == apiVersionV2 {
+		success = service.v2.Remove(m)
+	} else {
+		success = service.removeV1(m)
+	}
+	metrics.ObserveProviderRequest(statusCakeProviderLabel, operationRemove, start, success)
+}
+
+func (service *StatusCakeMonitorService) removeV1(m models.Monitor) bool {
 	u, err := url.Parse(service.url)
 	if err != nil {
 		log.Error(err, "Unable to Parse monitor URL")
-		return
+		return false
 	}
 	u.Path = "/API/Tests/Details"
 	u.Scheme = "https"
@@ -346,21 +527,21 @@ func (service *StatusCakeMonitorService) Remove(m models.Monitor) {
 	req, err := http.NewRequest("DELETE", u.String(), nil)
 	if err != nil {
 		log.Error(err, "Unable to create http request")
-		return
+		return false
 	}
 	req.Header.Add("API", service.apiKey)
 	req.Header.Add("Username", service.username)
 	resp, err := service.client.Do(req)
 	if err != nil {
 		log.Error(err, "Unable to make HTTP call")
-		return
+		return false
 	}
 	if resp.StatusCode == http.StatusOK {
 		var fa StatusCakeUpsertResponse
 		err := json.NewDecoder(resp.Body).Decode(&fa)
 		if err != nil {
 			log.Error(err, "Unable to decode http response")
-			return
+			return false
 		}
 		if fa.Success {
 			log.Info("Monitor Deleted: " + m.ID)
@@ -368,7 +549,8 @@ func (service *StatusCakeMonitorService) Remove(m models.Monitor) {
 			log.V(1).Info("Monitor couldn't be deleted: " + m.Name)
 			log.V(1).Info(fa.Message)
 		}
-	} else {
-		log.Error(nil, "Delete Request failed for name: "+m.Name)
+		return fa.Success
 	}
+	log.Error(nil, "Delete Request failed for name: "+m.Name)
+	return false
 }
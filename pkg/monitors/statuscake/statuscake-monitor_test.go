@@ -0,0 +1,151 @@
+package statuscake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	endpointmonitorv1alpha1 "github.com/stakater/IngressMonitorController/v2/api/v1alpha1"
+	"github.com/stakater/IngressMonitorController/v2/pkg/models"
+)
+
+// newTestV1Service spins up a TLS test server standing in for /API/Tests/Details,
+// since getTestDetails always targets https regardless of the scheme in the
+// configured URL.
+func newTestV1Service(t *testing.T, handler http.HandlerFunc) (*StatusCakeMonitorService, func()) {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	service := &StatusCakeMonitorService{
+		apiKey: "test-key",
+		url:    server.URL,
+		client: server.Client(),
+	}
+	return service, server.Close
+}
+
+func TestFormsEqualNoDrift(t *testing.T) {
+	m := models.Monitor{
+		Name: "test-monitor",
+		URL:  "https://example.com",
+		Config: &endpointmonitorv1alpha1.StatusCakeConfig{
+			CheckRate: 500,
+			TestType:  "HTTP",
+		},
+	}
+
+	oldForm := buildUpsertForm(m, "cgroup-1")
+	newForm := buildUpsertForm(m, "cgroup-1")
+
+	if !formsEqual(oldForm, newForm) {
+		t.Errorf("expected identical monitors to produce equal forms, got oldForm=%v newForm=%v", oldForm, newForm)
+	}
+}
+
+func TestFormsEqualSingleFieldDrift(t *testing.T) {
+	oldMonitor := models.Monitor{
+		Name: "test-monitor",
+		URL:  "https://example.com",
+		Config: &endpointmonitorv1alpha1.StatusCakeConfig{
+			CheckRate: 500,
+			TestType:  "HTTP",
+		},
+	}
+	newMonitor := oldMonitor
+	newMonitor.Config = &endpointmonitorv1alpha1.StatusCakeConfig{
+		CheckRate: 300,
+		TestType:  "HTTP",
+	}
+
+	oldForm := buildUpsertForm(oldMonitor, "cgroup-1")
+	newForm := buildUpsertForm(newMonitor, "cgroup-1")
+
+	if formsEqual(oldForm, newForm) {
+		t.Errorf("expected a CheckRate drift to produce different forms, got oldForm=%v newForm=%v", oldForm, newForm)
+	}
+}
+
+func TestStatusCakeMonitorServiceEqualNoDrift(t *testing.T) {
+	monitor := models.Monitor{
+		ID:   "123",
+		Name: "test-monitor",
+		URL:  "https://example.com",
+		Config: &endpointmonitorv1alpha1.StatusCakeConfig{
+			CheckRate: 300,
+			TestType:  "HTTP",
+		},
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("TestID"); got != "123" {
+			t.Errorf("expected TestID=123, got %q", got)
+		}
+		json.NewEncoder(w).Encode(StatusCakeTestDetails{
+			TestID:      123,
+			TestType:    "HTTP",
+			WebsiteName: "test-monitor",
+			WebsiteURL:  "https://example.com",
+			CheckRate:   300,
+		})
+	}
+
+	service, closeFn := newTestV1Service(t, handler)
+	defer closeFn()
+
+	if !service.Equal(monitor, monitor) {
+		t.Error("expected an unchanged monitor to be reported as equal")
+	}
+}
+
+func TestStatusCakeMonitorServiceEqualSingleFieldDrift(t *testing.T) {
+	oldMonitor := models.Monitor{
+		ID:   "123",
+		Name: "test-monitor",
+		URL:  "https://example.com",
+		Config: &endpointmonitorv1alpha1.StatusCakeConfig{
+			CheckRate: 300,
+			TestType:  "HTTP",
+		},
+	}
+	newMonitor := oldMonitor
+	newMonitor.Config = &endpointmonitorv1alpha1.StatusCakeConfig{
+		CheckRate: 500,
+		TestType:  "HTTP",
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(StatusCakeTestDetails{
+			TestID:      123,
+			TestType:    "HTTP",
+			WebsiteName: "test-monitor",
+			WebsiteURL:  "https://example.com",
+			CheckRate:   300,
+		})
+	}
+
+	service, closeFn := newTestV1Service(t, handler)
+	defer closeFn()
+
+	if service.Equal(oldMonitor, newMonitor) {
+		t.Error("expected a CheckRate drift to make Equal report false")
+	}
+}
+
+func TestFormsEqualNilVsZeroValueProviderConfig(t *testing.T) {
+	withNilConfig := models.Monitor{
+		Name: "test-monitor",
+		URL:  "https://example.com",
+	}
+	withZeroValueConfig := models.Monitor{
+		Name:   "test-monitor",
+		URL:    "https://example.com",
+		Config: &endpointmonitorv1alpha1.StatusCakeConfig{},
+	}
+
+	nilForm := buildUpsertForm(withNilConfig, "cgroup-1")
+	zeroValueForm := buildUpsertForm(withZeroValueConfig, "cgroup-1")
+
+	if !formsEqual(nilForm, zeroValueForm) {
+		t.Errorf("expected a nil provider config and a zero-value provider config to produce equal forms, got nilForm=%v zeroValueForm=%v", nilForm, zeroValueForm)
+	}
+}
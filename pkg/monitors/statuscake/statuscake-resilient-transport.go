@@ -0,0 +1,227 @@
+package statuscake
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stakater/IngressMonitorController/v2/pkg/config"
+	"github.com/stakater/IngressMonitorController/v2/pkg/metrics"
+)
+
+// errCircuitOpen is returned by resilientTransport.RoundTrip while the breaker is
+// open, so Add/Update/Remove/GetAll fail fast and the controller requeues instead
+// of hammering a StatusCake account that's already over quota.
+var errCircuitOpen = errors.New("statuscake: circuit breaker open, refusing request")
+
+// errRateLimitExhausted is returned when every retry attempt for a 429 response
+// is used up, so the caller sees a real error instead of a response whose body
+// was already closed by the retry loop.
+var errRateLimitExhausted = errors.New("statuscake: rate limit retries exhausted")
+
+const (
+	defaultMaxRetries                 = 3
+	defaultBaseBackoff                = 500 * time.Millisecond
+	defaultMaxBackoff                 = 30 * time.Second
+	defaultCircuitBreakerThreshold    = 5
+	defaultCircuitBreakerResetTimeout = 60 * time.Second
+)
+
+// circuitBreaker trips after a run of consecutive request failures (5xx,
+// network errors, or retries exhausted) and stays open for resetTimeout before
+// letting requests through again.
+type circuitBreaker struct {
+	mu               sync.Mutex
+	threshold        int
+	resetTimeout     time.Duration
+	consecutiveFails int
+	openedAt         time.Time
+}
+
+func newCircuitBreaker(threshold int, resetTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, resetTimeout: resetTimeout}
+}
+
+// allow reports whether a request may proceed: the breaker is closed, or it
+// tripped long enough ago that we allow a probe request through.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.consecutiveFails < b.threshold || time.Since(b.openedAt) >= b.resetTimeout
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFails = 0
+}
+
+func (b *circuitBreaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFails++
+	if b.consecutiveFails >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.consecutiveFails >= b.threshold && time.Since(b.openedAt) < b.resetTimeout
+}
+
+// resilientTransport wraps an http.RoundTripper with StatusCake rate-limit
+// awareness (Retry-After / X-RateLimit-Reset), exponential backoff with jitter on
+// 5xx and network errors, and a circuit breaker that trips after too many
+// consecutive failures.
+type resilientTransport struct {
+	next          http.RoundTripper
+	maxRetries    int
+	baseBackoff   time.Duration
+	maxBackoff    time.Duration
+	breaker       *circuitBreaker
+	providerLabel string
+}
+
+// newResilientTransport builds a resilientTransport from a provider's retry,
+// backoff and circuit breaker settings, falling back to sane defaults when they
+// are left unset.
+func newResilientTransport(p config.Provider, providerLabel string) *resilientTransport {
+	maxRetries := p.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	baseBackoff := time.Duration(p.BaseBackoffMilliseconds) * time.Millisecond
+	if baseBackoff <= 0 {
+		baseBackoff = defaultBaseBackoff
+	}
+
+	maxBackoff := time.Duration(p.MaxBackoffSeconds) * time.Second
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	breakerThreshold := p.CircuitBreakerThreshold
+	if breakerThreshold <= 0 {
+		breakerThreshold = defaultCircuitBreakerThreshold
+	}
+
+	resetTimeout := time.Duration(p.CircuitBreakerResetSeconds) * time.Second
+	if resetTimeout <= 0 {
+		resetTimeout = defaultCircuitBreakerResetTimeout
+	}
+
+	return &resilientTransport{
+		next:          http.DefaultTransport,
+		maxRetries:    maxRetries,
+		baseBackoff:   baseBackoff,
+		maxBackoff:    maxBackoff,
+		breaker:       newCircuitBreaker(breakerThreshold, resetTimeout),
+		providerLabel: providerLabel,
+	}
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.breaker.allow() {
+		metrics.CircuitBreakerOpen.WithLabelValues(t.providerLabel).Set(1)
+		return nil, errCircuitOpen
+	}
+
+	var resp *http.Response
+	var err error
+	for attempt := 0; attempt <= t.maxRetries; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+		resp, err = t.next.RoundTrip(attemptReq)
+
+		if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+			if attempt == t.maxRetries {
+				break
+			}
+			wait := retryAfter(resp)
+			resp.Body.Close()
+			time.Sleep(wait)
+			continue
+		}
+
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			t.breaker.recordSuccess()
+			metrics.CircuitBreakerOpen.WithLabelValues(t.providerLabel).Set(0)
+			recordQuota(resp, t.providerLabel)
+			return resp, nil
+		}
+
+		if attempt == t.maxRetries {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		time.Sleep(backoffWithJitter(t.baseBackoff, t.maxBackoff, attempt))
+	}
+
+	t.breaker.recordFailure()
+	if t.breaker.isOpen() {
+		metrics.CircuitBreakerOpen.WithLabelValues(t.providerLabel).Set(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		resp.Body.Close()
+		return nil, errRateLimitExhausted
+	}
+	return resp, nil
+}
+
+// retryAfter computes how long to wait before retrying a 429 response, preferring
+// the standard Retry-After header (seconds) and falling back to StatusCake's
+// X-RateLimit-Reset (a Unix timestamp of when the window opens again).
+func retryAfter(resp *http.Response) time.Duration {
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil && reset > 0 {
+		if wait := time.Until(time.Unix(reset, 0)); wait > 0 {
+			return wait
+		}
+	}
+	return defaultBaseBackoff
+}
+
+// recordQuota surfaces the remaining quota StatusCake reports on a response, if
+// any, through the metrics subsystem.
+func recordQuota(resp *http.Response, providerLabel string) {
+	remaining, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+	metrics.ProviderQuotaRemaining.WithLabelValues(providerLabel).Set(remaining)
+}
+
+// backoffWithJitter returns an exponentially growing delay, capped at maxBackoff,
+// with up to base worth of random jitter added to avoid synchronized retries.
+func backoffWithJitter(base time.Duration, maxBackoff time.Duration, attempt int) time.Duration {
+	backoff := base * time.Duration(1<<uint(attempt))
+	if backoff > maxBackoff || backoff <= 0 {
+		backoff = maxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(base) + 1))
+	total := backoff + jitter
+	if total > maxBackoff {
+		total = maxBackoff
+	}
+	return total
+}
@@ -0,0 +1,105 @@
+package statuscake
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stakater/IngressMonitorController/v2/pkg/config"
+)
+
+func TestResilientTransportRetriesOn429ThenSucceeds(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	transport := newResilientTransport(config.Provider{}, "statuscake")
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if attempts != 2 {
+		t.Errorf("expected the request to be retried once after a 429, got %d attempts", attempts)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected eventual 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestResilientTransportReturnsErrorWhen429RetriesExhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	transport := newResilientTransport(config.Provider{MaxRetries: 1}, "statuscake")
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(server.URL)
+	if !errors.Is(err, errRateLimitExhausted) {
+		t.Fatalf("expected errRateLimitExhausted, got resp=%v err=%v", resp, err)
+	}
+	if resp != nil {
+		t.Errorf("expected a nil response once retries are exhausted, got %v", resp)
+	}
+}
+
+func TestResilientTransportCircuitBreakerTripsAfterConsecutiveFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	transport := newResilientTransport(config.Provider{
+		MaxRetries:                 0,
+		CircuitBreakerThreshold:    2,
+		CircuitBreakerResetSeconds: 3600,
+	}, "statuscake")
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("unexpected error on attempt %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	if !transport.breaker.isOpen() {
+		t.Fatal("expected the circuit breaker to be open after consecutive failures")
+	}
+
+	if _, err := client.Get(server.URL); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("expected errCircuitOpen once the breaker is open, got %v", err)
+	}
+}
+
+func TestBackoffWithJitterRespectsMax(t *testing.T) {
+	base := 100 * time.Millisecond
+	max := 200 * time.Millisecond
+
+	for attempt := 0; attempt < 10; attempt++ {
+		got := backoffWithJitter(base, max, attempt)
+		if got > max {
+			t.Errorf("attempt %d: backoff %v exceeded max %v", attempt, got, max)
+		}
+		if got < 0 {
+			t.Errorf("attempt %d: backoff %v was negative", attempt, got)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package statuscake
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	endpointmonitorv1alpha1 "github.com/stakater/IngressMonitorController/v2/api/v1alpha1"
+	"github.com/stakater/IngressMonitorController/v2/pkg/models"
+)
+
+const (
+	apiVersionV1 = "v1"
+	apiVersionV2 = "v2"
+
+	statusCakeV2UptimePath = "/v1/uptime"
+	statusCakeV2PageLimit  = 100
+)
+
+// defaultV2StatusCodesCSV is the v2 equivalent of the long StatusCodes default list
+// built in buildUpsertForm, flattened into the comma-separated form the v2 API
+// expects for status_codes_csv.
+const defaultV2StatusCodesCSV = "204,205,206,303,305,400,401,402,403,404,405,406,407,408,409,410,411,412,413,414,415,416,417,418,421,422,423,424,425,426,428,429,431,444,451,499,500,501,502,503,504,505,506,507,508,509,510,511,520,521,522,523,524,525,526,527,530,598,599"
+
+// StatusCakeV2UptimeTest is the v2 representation of an uptime test, as returned by
+// and sent to /v1/uptime.
+type StatusCakeV2UptimeTest struct {
+	ID                 string   `json:"id,omitempty"`
+	Name               string   `json:"name"`
+	WebsiteURL         string   `json:"website_url"`
+	TestType           string   `json:"test_type"`
+	CheckRate          int      `json:"check_rate"`
+	ContactGroups      []string `json:"contact_groups,omitempty"`
+	ConfirmationPeriod int      `json:"confirmation_period,omitempty"`
+	TriggerRate        int      `json:"trigger_rate,omitempty"`
+	Port               int      `json:"port,omitempty"`
+	Paused             bool     `json:"paused"`
+	FollowRedirects    bool     `json:"follow_redirects"`
+	EnableSSLAlert     bool     `json:"enable_ssl_alert"`
+	UseJar             bool     `json:"use_jar"`
+	StatusCodesCSV     string   `json:"status_codes_csv"`
+	Tags               []string `json:"tags,omitempty"`
+	Regions            []string `json:"regions,omitempty"`
+	BasicAuthUser      string   `json:"basic_auth_user,omitempty"`
+	BasicAuthPass      string   `json:"basic_auth_pass,omitempty"`
+}
+
+// statusCakeV2Metadata is the pagination envelope the v2 API attaches to list
+// responses.
+type statusCakeV2Metadata struct {
+	Page      int `json:"page"`
+	PerPage   int `json:"per_page"`
+	PageCount int `json:"page_count"`
+}
+
+type statusCakeV2ListUptimeResponse struct {
+	Data     []StatusCakeV2UptimeTest `json:"data"`
+	Metadata statusCakeV2Metadata     `json:"metadata"`
+}
+
+type statusCakeV2GetUptimeResponse struct {
+	Data StatusCakeV2UptimeTest `json:"data"`
+}
+
+type statusCakeV2UpsertResponse struct {
+	Data struct {
+		NewID string `json:"new_id"`
+	} `json:"data"`
+}
+
+// translateToV2UptimeTest translates a models.Monitor, together with its
+// StatusCakeConfig CRD fields, into the v2 uptime test schema. It mirrors
+// buildUpsertForm's fallbacks so v1 and v2 produce equivalent tests for the same
+// EndpointMonitor.
+func translateToV2UptimeTest(m models.Monitor, cgroup string) StatusCakeV2UptimeTest {
+	providerConfig, _ := m.Config.(*endpointmonitorv1alpha1.StatusCakeConfig)
+
+	t := StatusCakeV2UptimeTest{
+		Name:       m.Name,
+		WebsiteURL: m.URL,
+	}
+
+	if providerConfig != nil && providerConfig.CheckRate > 0 {
+		t.CheckRate = providerConfig.CheckRate
+	} else {
+		t.CheckRate = 300
+	}
+
+	if providerConfig != nil && len(providerConfig.TestType) > 0 {
+		t.TestType = providerConfig.TestType
+	} else {
+		t.TestType = "HTTP"
+	}
+
+	if providerConfig != nil && len(providerConfig.ContactGroup) > 0 {
+		t.ContactGroups = strings.Split(providerConfig.ContactGroup, ",")
+	} else if cgroup != "" {
+		t.ContactGroups = strings.Split(cgroup, ",")
+	}
+
+	if providerConfig != nil && len(providerConfig.NodeLocations) > 0 {
+		t.Regions = strings.Split(providerConfig.NodeLocations, ",")
+	}
+
+	if providerConfig != nil && len(providerConfig.StatusCodes) > 0 {
+		t.StatusCodesCSV = providerConfig.StatusCodes
+	} else {
+		t.StatusCodesCSV = defaultV2StatusCodesCSV
+	}
+
+	if providerConfig != nil {
+		t.TriggerRate = providerConfig.TriggerRate
+		t.ConfirmationPeriod = providerConfig.Confirmation
+		t.Paused = providerConfig.Paused
+		t.FollowRedirects = providerConfig.FollowRedirect
+		t.EnableSSLAlert = providerConfig.EnableSSLAlert
+		t.UseJar = providerConfig.RealBrowser
+		t.Port = providerConfig.Port
+
+		if len(providerConfig.TestTags) > 0 {
+			t.Tags = strings.Split(providerConfig.TestTags, ",")
+		}
+
+		if len(providerConfig.BasicAuthUser) > 0 {
+			basicPass := os.Getenv(providerConfig.BasicAuthUser)
+			if basicPass != "" {
+				t.BasicAuthUser = providerConfig.BasicAuthUser
+				t.BasicAuthPass = basicPass
+				log.Info("Basic auth requirement detected. Setting username and password")
+			} else {
+				log.Info("Error reading basic auth password from environment variable")
+			}
+		}
+	}
+
+	return t
+}
+
+// statusCakeV2UptimeTestToMonitor converts a v2 uptime test back into a
+// models.Monitor, so it can be compared against the desired state the same way
+// StatusCakeMonitorMonitorsToBaseMonitorsMapper does for the v1 API.
+func statusCakeV2UptimeTestToMonitor(t StatusCakeV2UptimeTest) models.Monitor {
+	return models.Monitor{
+		ID:   t.ID,
+		Name: t.Name,
+		URL:  t.WebsiteURL,
+		Config: &endpointmonitorv1alpha1.StatusCakeConfig{
+			CheckRate:      t.CheckRate,
+			TestType:       t.TestType,
+			ContactGroup:   strings.Join(t.ContactGroups, ","),
+			NodeLocations:  strings.Join(t.Regions, ","),
+			TriggerRate:    t.TriggerRate,
+			Confirmation:   t.ConfirmationPeriod,
+			RealBrowser:    t.UseJar,
+			FollowRedirect: t.FollowRedirects,
+			EnableSSLAlert: t.EnableSSLAlert,
+			Paused:         t.Paused,
+			StatusCodes:    t.StatusCodesCSV,
+			TestTags:       strings.Join(t.Tags, ","),
+			Port:           t.Port,
+			BasicAuthUser:  t.BasicAuthUser,
+		},
+	}
+}
+
+func statusCakeV2UptimeTestsToBaseMonitorsMapper(tests []StatusCakeV2UptimeTest) []models.Monitor {
+	monitors := make([]models.Monitor, 0, len(tests))
+	for _, t := range tests {
+		monitors = append(monitors, statusCakeV2UptimeTestToMonitor(t))
+	}
+	return monitors
+}
+
+// statusCakeV2Client talks to StatusCake's v2 REST API: Bearer auth, JSON bodies
+// and paginated list endpoints, as opposed to the legacy v1 form-encoded client.
+type statusCakeV2Client struct {
+	apiToken string
+	baseURL  string
+	cgroup   string
+	client   *http.Client
+}
+
+func newStatusCakeV2Client(apiToken string, baseURL string, cgroup string, client *http.Client) *statusCakeV2Client {
+	return &statusCakeV2Client{
+		apiToken: apiToken,
+		baseURL:  baseURL,
+		cgroup:   cgroup,
+		client:   client,
+	}
+}
+
+func (c *statusCakeV2Client) newRequest(method string, path string, body []byte) (*http.Request, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path
+	u.Scheme = "https"
+
+	var reader *bytes.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// GetAll fetches every uptime test, following v2 pagination until the last page
+// has been retrieved.
+func (c *statusCakeV2Client) GetAll() []models.Monitor {
+	var all []StatusCakeV2UptimeTest
+	page := 1
+	for {
+		req, err := c.newRequest("GET", statusCakeV2UptimePath, nil)
+		if err != nil {
+			log.Error(err, "Unable to create http request")
+			return nil
+		}
+		q := req.URL.Query()
+		q.Set("page", strconv.Itoa(page))
+		q.Set("limit", strconv.Itoa(statusCakeV2PageLimit))
+		req.URL.RawQuery = q.Encode()
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			log.Error(err, "Unable to retrieve monitor")
+			return nil
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			log.Error(nil, "GetAll Request failed with status code "+strconv.Itoa(resp.StatusCode))
+			return nil
+		}
+
+		var list statusCakeV2ListUptimeResponse
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			log.Error(err, "Unable to retrieve monitor")
+			return nil
+		}
+
+		all = append(all, list.Data...)
+		if list.Metadata.PageCount == 0 || page >= list.Metadata.PageCount {
+			break
+		}
+		page++
+	}
+	return statusCakeV2UptimeTestsToBaseMonitorsMapper(all)
+}
+
+func (c *statusCakeV2Client) GetByName(name string) (*models.Monitor, error) {
+	for _, monitor := range c.GetAll() {
+		if monitor.Name == name {
+			return &monitor, nil
+		}
+	}
+	return nil, errors.New("GetByName Request failed for name: " + name)
+}
+
+// Add creates a new uptime test via POST /v1/uptime.
+func (c *statusCakeV2Client) Add(m models.Monitor) bool {
+	body, err := json.Marshal(translateToV2UptimeTest(m, c.cgroup))
+	if err != nil {
+		log.Error(err, "Unable to encode monitor")
+		return false
+	}
+	req, err := c.newRequest("POST", statusCakeV2UptimePath, body)
+	if err != nil {
+		log.Error(err, "Unable to create http request")
+		return false
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Error(err, "Unable to make HTTP call")
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusOK {
+		var created statusCakeV2UpsertResponse
+		if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+			log.Error(err, "Unable to decode http response")
+			return false
+		}
+		log.Info("Monitor Added: " + created.Data.NewID)
+		return true
+	}
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Error(err, "Unable to read response")
+		return false
+	}
+	log.Error(nil, "Insert Request failed for name: "+m.Name+" with status code "+strconv.Itoa(resp.StatusCode))
+	log.Error(nil, string(bodyBytes))
+	return false
+}
+
+// Update updates an existing uptime test via PUT /v1/uptime/{id}.
+func (c *statusCakeV2Client) Update(m models.Monitor) bool {
+	body, err := json.Marshal(translateToV2UptimeTest(m, c.cgroup))
+	if err != nil {
+		log.Error(err, "Unable to encode monitor")
+		return false
+	}
+	req, err := c.newRequest("PUT", statusCakeV2UptimePath+"/"+m.ID, body)
+	if err != nil {
+		log.Error(err, "Unable to create http request")
+		return false
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Error(err, "Unable to make HTTP call")
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		log.Info("Monitor Updated: " + m.Name)
+		return true
+	}
+	log.Error(nil, "Update Request failed for name: "+m.Name)
+	return false
+}
+
+// Remove deletes an existing uptime test via DELETE /v1/uptime/{id}.
+func (c *statusCakeV2Client) Remove(m models.Monitor) bool {
+	req, err := c.newRequest("DELETE", statusCakeV2UptimePath+"/"+m.ID, nil)
+	if err != nil {
+		log.Error(err, "Unable to create http request")
+		return false
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Error(err, "Unable to make HTTP call")
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusOK {
+		log.Info("Monitor Deleted: " + m.ID)
+		return true
+	}
+	log.Error(nil, "Delete Request failed for name: "+m.Name)
+	return false
+}
+
+// Equal fetches the currently configured test via GET /v1/uptime/{id} and compares
+// it against newMonitor, both normalized through translateToV2UptimeTest.
+func (c *statusCakeV2Client) Equal(oldMonitor models.Monitor, newMonitor models.Monitor) bool {
+	req, err := c.newRequest("GET", statusCakeV2UptimePath+"/"+oldMonitor.ID, nil)
+	if err != nil {
+		log.Error(err, "Unable to create http request")
+		return false
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Error(err, "Unable to make HTTP call")
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Error(nil, "Details Request failed for TestID: "+oldMonitor.ID)
+		return false
+	}
+
+	var current statusCakeV2GetUptimeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		log.Error(err, "Unable to decode http response")
+		return false
+	}
+
+	oldTest := translateToV2UptimeTest(statusCakeV2UptimeTestToMonitor(current.Data), c.cgroup)
+	newTest := translateToV2UptimeTest(newMonitor, c.cgroup)
+	return reflect.DeepEqual(oldTest, newTest)
+}
@@ -0,0 +1,119 @@
+package statuscake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	endpointmonitorv1alpha1 "github.com/stakater/IngressMonitorController/v2/api/v1alpha1"
+	"github.com/stakater/IngressMonitorController/v2/pkg/models"
+)
+
+// newTestV2Client spins up a TLS test server, since newRequest always targets
+// https regardless of the scheme in the configured URL.
+func newTestV2Client(t *testing.T, handler http.HandlerFunc) (*statusCakeV2Client, func()) {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	client := newStatusCakeV2Client("test-token", server.URL, "", server.Client())
+	return client, server.Close
+}
+
+func TestStatusCakeV2ClientGetAllFollowsPagination(t *testing.T) {
+	requestedPages := []string{}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-token" {
+			t.Errorf("expected Bearer auth header, got %q", auth)
+		}
+		page := r.URL.Query().Get("page")
+		requestedPages = append(requestedPages, page)
+
+		var resp statusCakeV2ListUptimeResponse
+		if page == "1" {
+			resp = statusCakeV2ListUptimeResponse{
+				Data:     []StatusCakeV2UptimeTest{{ID: "1", Name: "first"}},
+				Metadata: statusCakeV2Metadata{Page: 1, PerPage: 1, PageCount: 2},
+			}
+		} else {
+			resp = statusCakeV2ListUptimeResponse{
+				Data:     []StatusCakeV2UptimeTest{{ID: "2", Name: "second"}},
+				Metadata: statusCakeV2Metadata{Page: 2, PerPage: 1, PageCount: 2},
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+
+	client, closeFn := newTestV2Client(t, handler)
+	defer closeFn()
+
+	monitors := client.GetAll()
+	if len(monitors) != 2 {
+		t.Fatalf("expected 2 monitors across pages, got %d", len(monitors))
+	}
+	if len(requestedPages) != 2 {
+		t.Fatalf("expected 2 page requests, got %d (%v)", len(requestedPages), requestedPages)
+	}
+}
+
+func TestStatusCakeV2ClientGetAllReturnsNilOnNonOKStatus(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "invalid token"})
+	}
+
+	client, closeFn := newTestV2Client(t, handler)
+	defer closeFn()
+
+	if monitors := client.GetAll(); monitors != nil {
+		t.Errorf("expected a nil result on a non-200 GetAll response, got %v", monitors)
+	}
+}
+
+func TestStatusCakeV2ClientAdd(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if r.URL.Path != statusCakeV2UptimePath {
+			t.Errorf("expected path %s, got %s", statusCakeV2UptimePath, r.URL.Path)
+		}
+		var test StatusCakeV2UptimeTest
+		if err := json.NewDecoder(r.Body).Decode(&test); err != nil {
+			t.Fatalf("unable to decode request body: %v", err)
+		}
+		if test.StatusCodesCSV != defaultV2StatusCodesCSV {
+			t.Errorf("expected default status codes csv, got %q", test.StatusCodesCSV)
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(statusCakeV2UpsertResponse{
+			Data: struct {
+				NewID string `json:"new_id"`
+			}{NewID: "42"},
+		})
+	}
+
+	client, closeFn := newTestV2Client(t, handler)
+	defer closeFn()
+
+	client.Add(models.Monitor{Name: "test-monitor", URL: "https://example.com"})
+}
+
+func TestStatusCakeV2ClientEqualNoDrift(t *testing.T) {
+	providerConfig := &endpointmonitorv1alpha1.StatusCakeConfig{CheckRate: 300, TestType: "HTTP"}
+	monitor := models.Monitor{ID: "1", Name: "test-monitor", URL: "https://example.com", Config: providerConfig}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(statusCakeV2GetUptimeResponse{
+			Data: translateToV2UptimeTest(monitor, ""),
+		})
+	}
+
+	client, closeFn := newTestV2Client(t, handler)
+	defer closeFn()
+
+	if !client.Equal(monitor, monitor) {
+		t.Error("expected an unchanged monitor to be reported as equal")
+	}
+}